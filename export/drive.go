@@ -0,0 +1,81 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2/google"
+	drivev3 "google.golang.org/api/drive/v3"
+)
+
+// ToDrive uploads the raw csv files under rootPath/repo straight into the
+// Drive folder identified by folderID, using a resumable upload. Unlike
+// ToSpreadSheet it does no parsing of the CSVs - it's a lightweight
+// archival option that isn't bound by the Sheets 10M-cell limit.
+func ToDrive(ctx context.Context, rootPath string, repo string, folderID string) error {
+	if folderID == "" {
+		folderID = stargazersFolderID
+	}
+	reportFiles := findCSVFiles(rootPath, repo)
+
+	b, err := ioutil.ReadFile("client_secret.json")
+	if err != nil {
+		return fmt.Errorf("unable to read client secret file: %w", err)
+	}
+
+	config, err := google.ConfigFromJSON(b, drivev3.DriveFileScope)
+	if err != nil {
+		return fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+	client, err := getClient(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	srv, err := drivev3.New(client)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve Drive client: %w", err)
+	}
+
+	for _, f := range reportFiles {
+		if err := uploadCSV(ctx, srv, path.Join(rootPath, repo, f), folderID); err != nil {
+			return fmt.Errorf("uploading %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+// uploadCSV uploads a single csv file to folderID, preserving its on-disk
+// modification time as the Drive file's ModifiedTime.
+func uploadCSV(ctx context.Context, srv *drivev3.Service, csvPath string, folderID string) error {
+	info, err := os.Stat(csvPath)
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	file := &drivev3.File{
+		Name:         filepath.Base(csvPath),
+		Parents:      []string{folderID},
+		MimeType:     "text/csv",
+		ModifiedTime: info.ModTime().Format("2006-01-02T15:04:05.000Z07:00"),
+	}
+
+	return withRetry(ctx, func() error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := srv.Files.Create(file).Context(ctx).Media(f).Do()
+		return err
+	})
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// Exporter writes the per-repo CSV reports under rootPath/repo out to some
+// destination - a spreadsheet, a Drive folder, a SQLite database, a
+// stream of JSON Lines records, and so on.
+type Exporter interface {
+	Export(ctx context.Context, rootPath, repo string) error
+}
+
+// Options configures the exporters built by New. Only the fields relevant
+// to the requested kind need to be set.
+type Options struct {
+	// FolderID is the Drive folder sheets/CSVs are filed under. Defaults
+	// to stargazersFolderID when empty.
+	FolderID string
+	// DBPath is the SQLite database file used by the "sqlite" exporter.
+	DBPath string
+	// OutDir is the directory JSON Lines files are written to by the
+	// "jsonlines" exporter.
+	OutDir string
+}
+
+// sheetsExporter adapts ToSpreadSheet to the Exporter interface.
+type sheetsExporter struct {
+	folderID string
+}
+
+func (e sheetsExporter) Export(ctx context.Context, rootPath, repo string) error {
+	_, err := ToSpreadSheet(ctx, rootPath, repo, WithFolderID(e.folderID))
+	return err
+}
+
+// driveExporter adapts ToDrive to the Exporter interface.
+type driveExporter struct {
+	folderID string
+}
+
+func (e driveExporter) Export(ctx context.Context, rootPath, repo string) error {
+	return ToDrive(ctx, rootPath, repo, e.folderID)
+}
+
+// New builds the Exporter identified by kind: "sheets", "drive", "sqlite"
+// or "jsonlines". Callers that want several sinks in one pass (e.g. a
+// --export=sheets,sqlite flag) should split kind on "," and call New once
+// per kind.
+func New(kind string, opts Options) (Exporter, error) {
+	switch kind {
+	case "sheets":
+		folderID := opts.FolderID
+		if folderID == "" {
+			folderID = stargazersFolderID
+		}
+		return sheetsExporter{folderID: folderID}, nil
+	case "drive":
+		folderID := opts.FolderID
+		if folderID == "" {
+			folderID = stargazersFolderID
+		}
+		return driveExporter{folderID: folderID}, nil
+	case "sqlite":
+		return &SQLiteExporter{DBPath: opts.DBPath}, nil
+	case "jsonlines":
+		return &JSONLinesExporter{OutDir: opts.OutDir}, nil
+	default:
+		return nil, fmt.Errorf("export: unknown exporter kind %q", kind)
+	}
+}
@@ -0,0 +1,141 @@
+// Package gsheets contains small helpers around the Sheets v4 API that are
+// shared between the exporters in the parent export package. They wrap the
+// BatchUpdate/Values calls so callers don't have to hand-build the request
+// envelopes for common operations (adding a sheet, appending rows, reading a
+// range back).
+package gsheets
+
+import (
+	"math"
+	"strconv"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/sheets/v4"
+)
+
+// ReadSheetValuesRange reads rangeA1 (e.g. "Sheet1!A1:Z") from spreadsheetID
+// and returns the raw cell values as returned by the Sheets API.
+func ReadSheetValuesRange(ctx context.Context, srv *sheets.Service, spreadsheetID, rangeA1 string) ([][]interface{}, error) {
+	resp, err := srv.Spreadsheets.Values.Get(spreadsheetID, rangeA1).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Values, nil
+}
+
+// ClearSheet clears every cell on the sheet titled sheetTitle within
+// spreadsheetID, so a subsequent UpdateCells starting at the top-left
+// corner can't leave stale rows behind when the new data has fewer rows
+// than what was there before.
+func ClearSheet(ctx context.Context, srv *sheets.Service, spreadsheetID, sheetTitle string) error {
+	_, err := srv.Spreadsheets.Values.Clear(spreadsheetID, sheetTitle, &sheets.ClearValuesRequest{}).Context(ctx).Do()
+	return err
+}
+
+// AddNewSheet adds a new sheet titled title to spreadsheetID and returns the
+// properties of the sheet that was created.
+func AddNewSheet(ctx context.Context, srv *sheets.Service, spreadsheetID, title string) (*sheets.SheetProperties, error) {
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				AddSheet: &sheets.AddSheetRequest{
+					Properties: &sheets.SheetProperties{
+						Title: title,
+					},
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Replies[0].AddSheet.Properties, nil
+}
+
+// DeleteSheet removes the sheet identified by sheetID from spreadsheetID.
+func DeleteSheet(ctx context.Context, srv *sheets.Service, spreadsheetID string, sheetID int64) error {
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				DeleteSheet: &sheets.DeleteSheetRequest{
+					SheetId: sheetID,
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	return err
+}
+
+// AppendRows appends rows to the end of the sheet identified by sheetID
+// within spreadsheetID using an AppendCells request.
+func AppendRows(ctx context.Context, srv *sheets.Service, spreadsheetID string, sheetID int64, rows []*sheets.RowData) error {
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				AppendCells: &sheets.AppendCellsRequest{
+					SheetId: sheetID,
+					Rows:    rows,
+					Fields:  "userEnteredValue",
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	return err
+}
+
+// UpdateCells overwrites rows starting at row 0, column 0 of the sheet
+// identified by sheetID within spreadsheetID using an UpdateCells request.
+func UpdateCells(ctx context.Context, srv *sheets.Service, spreadsheetID string, sheetID int64, rows []*sheets.RowData) error {
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{
+			{
+				UpdateCells: &sheets.UpdateCellsRequest{
+					Start: &sheets.GridCoordinate{
+						SheetId:     sheetID,
+						RowIndex:    0,
+						ColumnIndex: 0,
+					},
+					Rows:   rows,
+					Fields: "userEnteredValue",
+				},
+			},
+		},
+	}).Context(ctx).Do()
+	return err
+}
+
+// ValuesToCellData converts CSV records into sheet RowData, typing each
+// cell as a number or a boolean when the raw string parses as one and
+// falling back to a plain string otherwise. This lets charts and SUM/AVG
+// formulas work directly against the exported sheet instead of every cell
+// coming through as text.
+func ValuesToCellData(records [][]string) []*sheets.RowData {
+	rows := make([]*sheets.RowData, 0, len(records))
+	for _, record := range records {
+		cells := make([]*sheets.CellData, 0, len(record))
+		for _, value := range record {
+			cells = append(cells, &sheets.CellData{
+				UserEnteredValue: valueToExtendedValue(value),
+			})
+		}
+		rows = append(rows, &sheets.RowData{Values: cells})
+	}
+	return rows
+}
+
+func valueToExtendedValue(value string) *sheets.ExtendedValue {
+	// Only the literal "true" and "false" count as booleans, not
+	// everything strconv.ParseBool accepts: it also takes "0" and "1",
+	// which are common star/fork/contribution counts and must stay
+	// numbers rather than become checkboxes. ParseFloat itself accepts
+	// "NaN" and "Inf"/"+Inf"/"-Inf", so those are rejected explicitly -
+	// they're text values in a CSV, not numbers.
+	if n, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+		return &sheets.ExtendedValue{NumberValue: &n}
+	}
+	if value == "true" || value == "false" {
+		b := value == "true"
+		return &sheets.ExtendedValue{BoolValue: &b}
+	}
+	return &sheets.ExtendedValue{StringValue: &value}
+}
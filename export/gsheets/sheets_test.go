@@ -0,0 +1,54 @@
+package gsheets
+
+import "testing"
+
+func TestValueToExtendedValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  sheetsExtendedValueWant
+	}{
+		{"integer", "42", sheetsExtendedValueWant{number: ptr(42.0)}},
+		{"float", "3.14", sheetsExtendedValueWant{number: ptr(3.14)}},
+		{"zero", "0", sheetsExtendedValueWant{number: ptr(0.0)}},
+		{"one", "1", sheetsExtendedValueWant{number: ptr(1.0)}},
+		{"true", "true", sheetsExtendedValueWant{boolean: ptrBool(true)}},
+		{"false", "false", sheetsExtendedValueWant{boolean: ptrBool(false)}},
+		{"string", "octocat", sheetsExtendedValueWant{str: ptrStr("octocat")}},
+		{"NaN is text", "NaN", sheetsExtendedValueWant{str: ptrStr("NaN")}},
+		{"Inf is text", "Inf", sheetsExtendedValueWant{str: ptrStr("Inf")}},
+		{"+Inf is text", "+Inf", sheetsExtendedValueWant{str: ptrStr("+Inf")}},
+		{"-Inf is text", "-Inf", sheetsExtendedValueWant{str: ptrStr("-Inf")}},
+		{"empty string is text", "", sheetsExtendedValueWant{str: ptrStr("")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := valueToExtendedValue(tt.value)
+			switch {
+			case tt.want.number != nil:
+				if got.NumberValue == nil || *got.NumberValue != *tt.want.number {
+					t.Errorf("valueToExtendedValue(%q) = %+v, want NumberValue %v", tt.value, got, *tt.want.number)
+				}
+			case tt.want.boolean != nil:
+				if got.BoolValue == nil || *got.BoolValue != *tt.want.boolean {
+					t.Errorf("valueToExtendedValue(%q) = %+v, want BoolValue %v", tt.value, got, *tt.want.boolean)
+				}
+			case tt.want.str != nil:
+				if got.StringValue == nil || *got.StringValue != *tt.want.str {
+					t.Errorf("valueToExtendedValue(%q) = %+v, want StringValue %q", tt.value, got, *tt.want.str)
+				}
+			}
+		})
+	}
+}
+
+type sheetsExtendedValueWant struct {
+	number  *float64
+	boolean *bool
+	str     *string
+}
+
+func ptr(f float64) *float64  { return &f }
+func ptrBool(b bool) *bool    { return &b }
+func ptrStr(s string) *string { return &s }
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// JSONLinesExporter streams each CSV report row out as a single JSON
+// object per line (newline-delimited JSON), one .jsonl file per report,
+// ready to be picked up by a `bq load --source_format=NEWLINE_DELIMITED_JSON`
+// or an ELK ingest pipeline.
+type JSONLinesExporter struct {
+	// OutDir is the directory the .jsonl files are written to.
+	OutDir string
+}
+
+// Export implements Exporter.
+func (e *JSONLinesExporter) Export(ctx context.Context, rootPath, repo string) error {
+	for _, f := range findCSVFiles(rootPath, repo) {
+		if err := e.exportFile(path.Join(rootPath, repo, f), f); err != nil {
+			return fmt.Errorf("jsonlines: %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+func (e *JSONLinesExporter) exportFile(csvPath, name string) error {
+	in, err := os.Open(csvPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	outPath := filepath.Join(e.OutDir, strings.TrimSuffix(name, filepath.Ext(name))+".jsonl")
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	r := csv.NewReader(bufio.NewReader(in))
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	enc := json.NewEncoder(w)
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		row := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				row[col] = record[i]
+			}
+		}
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
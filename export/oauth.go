@@ -0,0 +1,247 @@
+package export
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+// defaultTokenPath returns ~/.config/stargazers/token.json, or token.json
+// in the working directory when the home directory can't be determined.
+// It doesn't create the directory; saveTo does that when a token is saved.
+func defaultTokenPath() string {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "token.json"
+	}
+	return filepath.Join(dir, ".config", "stargazers", "token.json")
+}
+
+// TokenManager caches an OAuth2 token on disk and hands back a TokenSource
+// that transparently refreshes it (and re-persists the refreshed token)
+// once it expires.
+type TokenManager struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewTokenManager returns a TokenManager backed by path. An empty path
+// defaults to defaultTokenPath().
+func NewTokenManager(path string) *TokenManager {
+	if path == "" {
+		path = defaultTokenPath()
+	}
+	return &TokenManager{path: path}
+}
+
+// Get returns a TokenSource for config, running the interactive loopback
+// OAuth flow the first time it's called and reusing - and transparently
+// refreshing - the cached token on every call after that. The token is
+// cached under a path derived from config's scopes, so asking for a
+// different set of scopes (e.g. ToDrive's drive.file vs ToSpreadSheet's
+// spreadsheets+drive) never reuses a token that wasn't granted them -
+// it re-triggers the OAuth flow instead of failing downstream with a 403.
+func (tm *TokenManager) Get(ctx context.Context, config *oauth2.Config) (oauth2.TokenSource, error) {
+	path := scopedTokenPath(tm.path, config.Scopes)
+	tok, err := tm.loadToken(path)
+	if err != nil {
+		tok, err = getTokenFromWeb(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve token from web: %w", err)
+		}
+		if err := tm.saveTo(path, tok); err != nil {
+			return nil, fmt.Errorf("unable to cache oauth token: %w", err)
+		}
+	}
+	base := config.TokenSource(ctx, tok)
+	return oauth2.ReuseTokenSource(tok, &cachingTokenSource{tm: tm, path: path, base: base, last: tok}), nil
+}
+
+// scopedTokenPath derives a cache file path from base that's unique to
+// scopes, so a token cached for one set of OAuth scopes can never be
+// silently handed to a caller asking for a different set.
+func scopedTokenPath(base string, scopes []string) string {
+	sorted := append([]string(nil), scopes...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	hash := base64.RawURLEncoding.EncodeToString(sum[:])[:8]
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-" + hash + ext
+}
+
+// loadToken reads the cached token from path.
+func (tm *TokenManager) loadToken(path string) (*oauth2.Token, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tok := &oauth2.Token{}
+	if err := json.NewDecoder(f).Decode(tok); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+// Save persists token to tm.path, creating the parent directory if needed.
+func (tm *TokenManager) Save(token *oauth2.Token) error {
+	return tm.saveTo(tm.path, token)
+}
+
+func (tm *TokenManager) saveTo(path string, token *oauth2.Token) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(token)
+}
+
+// cachingTokenSource wraps base and persists every freshly minted token to
+// tm at path, so a refreshed access token survives across runs.
+type cachingTokenSource struct {
+	tm   *TokenManager
+	path string
+	base oauth2.TokenSource
+	last *oauth2.Token
+}
+
+func (c *cachingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := c.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	if c.last == nil || tok.AccessToken != c.last.AccessToken {
+		if err := c.tm.saveTo(c.path, tok); err != nil {
+			log.Printf("Unable to cache refreshed oauth token: %v", err)
+		}
+		c.last = tok
+	}
+	return tok, nil
+}
+
+// getTokenFromWeb runs the browser-based OAuth2 flow: it serves the
+// redirect callback on an ephemeral loopback listener, opens the consent
+// screen in the user's browser, and exchanges the returned code - using
+// PKCE and state validation - for a token.
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config) (*oauth2.Token, error) {
+	verifier, challenge, err := generatePKCE()
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomURLSafeString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to start loopback listener: %w", err)
+	}
+	defer listener.Close()
+
+	config.RedirectURL = fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	type result struct {
+		code string
+		err  error
+	}
+	resultCh := make(chan result, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			resultCh <- result{err: fmt.Errorf("state mismatch")}
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		}
+		if errMsg := q.Get("error"); errMsg != "" {
+			resultCh <- result{err: fmt.Errorf("authorization denied: %s", errMsg)}
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "Authorization complete, you can close this tab and return to the terminal.")
+		resultCh <- result{code: q.Get("code")}
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	authURL := config.AuthCodeURL(state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	if err := openBrowser(authURL); err != nil {
+		fmt.Printf("Go to the following link in your browser:\n%v\n", authURL)
+	}
+
+	select {
+	case res := <-resultCh:
+		if res.err != nil {
+			return nil, res.err
+		}
+		return config.Exchange(ctx, res.code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// generatePKCE returns a random code_verifier and its S256 code_challenge.
+func generatePKCE() (verifier, challenge string, err error) {
+	verifier, err = randomURLSafeString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
+
+func randomURLSafeString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// openBrowser opens url in the user's default browser.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,30 @@
+package export
+
+// ExportResult describes the outcome of a successful ToSpreadSheet call.
+type ExportResult struct {
+	// SpreadsheetID is the ID of the spreadsheet that was created or
+	// updated.
+	SpreadsheetID string
+}
+
+// exportConfig holds the settings Option functions mutate.
+type exportConfig struct {
+	folderID string
+}
+
+// Option configures a ToSpreadSheet call.
+type Option func(*exportConfig)
+
+// WithFolderID overrides the Drive folder spreadsheets are filed under.
+// Defaults to stargazersFolderID.
+func WithFolderID(id string) Option {
+	return func(c *exportConfig) { c.folderID = id }
+}
+
+func newExportConfig(opts []Option) *exportConfig {
+	cfg := &exportConfig{folderID: stargazersFolderID}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+// maxRetries is the number of extra attempts withRetry makes before giving
+// up and returning the last error it saw.
+const maxRetries = 5
+
+// withRetry calls fn, retrying with exponential backoff when the Google
+// API responds with a 429 or a 5xx error. It honors a Retry-After header
+// on the response when the API sends one.
+func withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		wait, retryable := retryDelay(err, attempt)
+		if !retryable || attempt >= maxRetries {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// retryDelay reports whether err looks like a transient Google API error
+// and, if so, how long to wait before retrying it.
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	gerr, ok := err.(*googleapi.Error)
+	if !ok || (gerr.Code != http.StatusTooManyRequests && gerr.Code < http.StatusInternalServerError) {
+		return 0, false
+	}
+	if ra := gerr.Header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	backoff := 500 * time.Millisecond << uint(attempt)
+	return backoff + time.Duration(rand.Int63n(int64(backoff))), true
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestRetryDelay(t *testing.T) {
+	t.Run("plain error is not retryable", func(t *testing.T) {
+		_, retryable := retryDelay(errors.New("boom"), 0)
+		if retryable {
+			t.Errorf("retryDelay() retryable = true, want false for a non-googleapi error")
+		}
+	})
+
+	t.Run("404 is not retryable", func(t *testing.T) {
+		gerr := &googleapi.Error{Code: http.StatusNotFound}
+		_, retryable := retryDelay(gerr, 0)
+		if retryable {
+			t.Errorf("retryDelay() retryable = true, want false for a 404")
+		}
+	})
+
+	t.Run("429 without Retry-After backs off exponentially", func(t *testing.T) {
+		gerr := &googleapi.Error{Code: http.StatusTooManyRequests, Header: http.Header{}}
+		for attempt := 0; attempt < 3; attempt++ {
+			wait, retryable := retryDelay(gerr, attempt)
+			if !retryable {
+				t.Fatalf("attempt %d: retryable = false, want true", attempt)
+			}
+			min := 500 * time.Millisecond << uint(attempt)
+			max := 2 * min
+			if wait < min || wait >= max {
+				t.Errorf("attempt %d: wait = %v, want in [%v, %v)", attempt, wait, min, max)
+			}
+		}
+	})
+
+	t.Run("500 honors Retry-After", func(t *testing.T) {
+		gerr := &googleapi.Error{
+			Code:   http.StatusInternalServerError,
+			Header: http.Header{"Retry-After": []string{"7"}},
+		}
+		wait, retryable := retryDelay(gerr, 0)
+		if !retryable {
+			t.Fatalf("retryable = false, want true")
+		}
+		if wait != 7*time.Second {
+			t.Errorf("wait = %v, want 7s", wait)
+		}
+	})
+}
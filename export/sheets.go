@@ -20,163 +20,281 @@ package export
 import (
 	"bufio"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
-	"time"
 
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	drive "google.golang.org/api/drive/v2"
 	"google.golang.org/api/sheets/v4"
+
+	"github.com/DrMegavolt/stargazers/export/gsheets"
 )
 
-// Retrieve a token, saves the token, then returns the generated client.
-func getClient(config *oauth2.Config) *http.Client {
-	tokFile := "token.json"
-	tok, err := tokenFromFile(tokFile)
+// getClient runs the OAuth2 flow for config - reusing and refreshing a
+// cached token when one is available - and returns an authenticated
+// client. The token is cached next to the default TokenManager path, in a
+// file named after config's scopes.
+func getClient(ctx context.Context, config *oauth2.Config) (*http.Client, error) {
+	ts, err := NewTokenManager("").Get(ctx, config)
 	if err != nil {
-		tok = getTokenFromWeb(config)
-		saveToken(tokFile, tok)
+		return nil, fmt.Errorf("unable to retrieve oauth token: %w", err)
 	}
-	return config.Client(context.Background(), tok)
+	return oauth2.NewClient(ctx, ts), nil
 }
 
-// Request a token from the web, then returns the retrieved token.
-func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
+// stargazersFolderID is the default Drive folder spreadsheets and CSVs are
+// filed under; override it with WithFolderID for ToSpreadSheet, or with
+// ToDrive's folderID argument.
+const stargazersFolderID = "144EFimPBTcoHnAzBpeoEcbqN-yeTLAqe"
 
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		log.Fatalf("Unable to read authorization code: %v", err)
-	}
+// ToSpreadSheet uploads all csv files under rootPath/repo as sheets in a
+// SpreadSheet. If a spreadsheet for repo already exists in the target Drive
+// folder it is reused and updated in-place (sheets are added, replaced or
+// removed via BatchUpdate) instead of creating a new spreadsheet every run,
+// so the spreadsheet ID - and anything built on top of it, like pivot
+// tables or bookmarks - stays stable across runs.
+func ToSpreadSheet(ctx context.Context, rootPath string, repo string, opts ...Option) (*ExportResult, error) {
+	cfg := newExportConfig(opts)
+	reportFiles := findCSVFiles(rootPath, repo)
 
-	tok, err := config.Exchange(oauth2.NoContext, authCode)
+	b, err := ioutil.ReadFile("client_secret.json")
 	if err != nil {
-		log.Fatalf("Unable to retrieve token from web: %v", err)
+		return nil, fmt.Errorf("unable to read client secret file: %w", err)
 	}
-	return tok
-}
 
-// Retrieves a token from a local file.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	defer f.Close()
+	// The cached token is keyed by scope set, so changing these scopes
+	// starts a fresh OAuth flow instead of reusing the old token.
+	config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets", "https://www.googleapis.com/auth/drive")
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+	client, err := getClient(ctx, config)
 	if err != nil {
 		return nil, err
 	}
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
-}
 
-// Saves a token to a file path.
-func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	defer f.Close()
+	srv, err := sheets.New(client)
 	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
+		return nil, fmt.Errorf("unable to retrieve Sheets client: %w", err)
+	}
+	driveSrv, err := drive.New(client)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve Drive client: %w", err)
 	}
-	json.NewEncoder(f).Encode(token)
-}
 
-// ToSpreadSheet uploads all csv files under rootPath/repo as sheets in a SpreadSheet
-func ToSpreadSheet(rootPath string, repo string) {
-	var reportFiles []string
-	filepath.Walk(rootPath, func(p string, f os.FileInfo, _ error) error {
-		if filepath.Ext(p) == ".csv" {
-			reportFiles = append(reportFiles, f.Name())
-		}
-		return nil
-	})
+	doc, err := findExistingSpreadsheet(ctx, driveSrv, srv, repo, cfg.folderID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to look up existing spreadsheet for %s: %w", repo, err)
+	}
 
-	b, err := ioutil.ReadFile("client_secret.json")
+	if doc == nil {
+		doc, err = createSpreadSheet(ctx, srv, driveSrv, rootPath, repo, reportFiles, cfg.folderID)
+	} else {
+		err = updateSpreadSheet(ctx, srv, doc, rootPath, repo, reportFiles)
+	}
 	if err != nil {
-		log.Fatalf("Unable to read client secret file: %v", err)
+		return nil, err
 	}
+	return &ExportResult{SpreadsheetID: doc.SpreadsheetId}, nil
+}
 
-	// If modifying these scopes, delete your previously saved client_secret.json.
-	config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets")
+// findExistingSpreadsheet looks for a spreadsheet named repo in folderID,
+// returning nil if none is found yet.
+func findExistingSpreadsheet(ctx context.Context, driveSrv *drive.Service, srv *sheets.Service, repo, folderID string) (*sheets.Spreadsheet, error) {
+	var lr *drive.FileList
+	err := withRetry(ctx, func() error {
+		var err error
+		lr, err = driveSrv.Files.List().
+			Q(fmt.Sprintf("'%s' in parents and title = '%s' and trashed = false", folderID, repo)).
+			Context(ctx).
+			Do()
+		return err
+	})
 	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
+		return nil, fmt.Errorf("listing files in folder %s: %w", folderID, err)
+	}
+	if len(lr.Items) == 0 {
+		return nil, nil
 	}
-	client := getClient(config)
 
-	srv, err := sheets.New(client)
+	var doc *sheets.Spreadsheet
+	err = withRetry(ctx, func() error {
+		var err error
+		doc, err = srv.Spreadsheets.Get(lr.Items[0].Id).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
-		log.Fatalf("Unable to retrieve Sheets client: %v", err)
+		return nil, fmt.Errorf("fetching spreadsheet %s: %w", lr.Items[0].Id, err)
 	}
-	fmt.Println(reportFiles)
-	newSheets := make([]*sheets.Sheet, 0)
+	return doc, nil
+}
 
+// createSpreadSheet creates a brand new spreadsheet named after repo, with
+// one sheet per CSV report file, and files it under folderID.
+func createSpreadSheet(ctx context.Context, srv *sheets.Service, driveSrv *drive.Service, rootPath, repo string, reportFiles []string, folderID string) (*sheets.Spreadsheet, error) {
+	newSheets := make([]*sheets.Sheet, 0, len(reportFiles))
 	for _, f := range reportFiles {
-
+		rows, err := buildRowsFromCsv(path.Join(rootPath, repo, f))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f, err)
+		}
 		newSheets = append(newSheets, &sheets.Sheet{
 			Properties: &sheets.SheetProperties{
 				Title: f,
 			},
 			Data: []*sheets.GridData{
-				&sheets.GridData{
-					RowData: buildRowsFromCsv(path.Join(rootPath, repo, f)),
+				{
+					RowData: rows,
 				},
 			},
 		})
 	}
-	currentTime := time.Now().Local()
-	doc, _ := srv.Spreadsheets.Create(&sheets.Spreadsheet{
-		Properties: &sheets.SpreadsheetProperties{
-			Title: currentTime.Format("2006-01-02"),
-		},
 
-		Sheets: newSheets,
-	}).Do()
+	var doc *sheets.Spreadsheet
+	err := withRetry(ctx, func() error {
+		var err error
+		doc, err = srv.Spreadsheets.Create(&sheets.Spreadsheet{
+			Properties: &sheets.SpreadsheetProperties{
+				Title: repo,
+			},
+			Sheets: newSheets,
+		}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating spreadsheet: %w", err)
+	}
 
-	driveSrv, err := drive.New(client)
+	err = withRetry(ctx, func() error {
+		_, err := driveSrv.Parents.Insert(doc.SpreadsheetId, &drive.ParentReference{
+			Id: folderID,
+		}).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("filing spreadsheet under folder %s: %w", folderID, err)
+	}
+	return doc, nil
+}
+
+// updateSpreadSheet refreshes doc in-place: sheets matching a report file
+// are cleared and rewritten with UpdateCells (clearing first so a report
+// that shrank since the last run doesn't leave stale rows behind), sheets
+// for report files that don't exist yet are added, and stale sheets left
+// over from a previous run with a different set of report files are
+// dropped - except the Sheets API refuses to delete the last remaining
+// sheet in a spreadsheet, so that never happens even if reportFiles is
+// empty.
+func updateSpreadSheet(ctx context.Context, srv *sheets.Service, doc *sheets.Spreadsheet, rootPath, repo string, reportFiles []string) error {
+	existing := make(map[string]int64, len(doc.Sheets))
+	for _, s := range doc.Sheets {
+		existing[s.Properties.Title] = s.Properties.SheetId
+	}
+	sheetCount := len(doc.Sheets)
 
-	lr, _ := driveSrv.Files.List().Do()
-	fmt.Println(lr)
-	refRes, _ := driveSrv.Parents.Insert(doc.SpreadsheetId, &drive.ParentReference{
-		Id: "144EFimPBTcoHnAzBpeoEcbqN-yeTLAqe",
-	}).Do()
-	fmt.Println(refRes)
+	wanted := make(map[string]bool, len(reportFiles))
+	for _, f := range reportFiles {
+		wanted[f] = true
+		rows, err := buildRowsFromCsv(path.Join(rootPath, repo, f))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", f, err)
+		}
+
+		sheetID, ok := existing[f]
+		if !ok {
+			var props *sheets.SheetProperties
+			err := withRetry(ctx, func() error {
+				var err error
+				props, err = gsheets.AddNewSheet(ctx, srv, doc.SpreadsheetId, f)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("adding sheet %s: %w", f, err)
+			}
+			sheetID = props.SheetId
+			sheetCount++
+		} else {
+			err := withRetry(ctx, func() error {
+				return gsheets.ClearSheet(ctx, srv, doc.SpreadsheetId, f)
+			})
+			if err != nil {
+				return fmt.Errorf("clearing sheet %s: %w", f, err)
+			}
+		}
+		err = withRetry(ctx, func() error {
+			return gsheets.UpdateCells(ctx, srv, doc.SpreadsheetId, sheetID, rows)
+		})
+		if err != nil {
+			return fmt.Errorf("updating sheet %s: %w", f, err)
+		}
+	}
+
+	for title, sheetID := range existing {
+		if wanted[title] {
+			continue
+		}
+		if sheetCount <= 1 {
+			// The Sheets API rejects deleting the last sheet in a
+			// spreadsheet; leave it in place rather than erroring out.
+			continue
+		}
+		err := withRetry(ctx, func() error {
+			return gsheets.DeleteSheet(ctx, srv, doc.SpreadsheetId, sheetID)
+		})
+		if err != nil {
+			return fmt.Errorf("deleting stale sheet %s: %w", title, err)
+		}
+		sheetCount--
+	}
+	return nil
+}
 
+// findCSVFiles returns the base names of every .csv file under
+// rootPath/repo. Scoping the walk to that one repo's subdirectory - rather
+// than all of rootPath - keeps a multi-repo rootPath from leaking other
+// repos' reports into this one's export.
+func findCSVFiles(rootPath, repo string) []string {
+	var reportFiles []string
+	filepath.Walk(path.Join(rootPath, repo), func(p string, f os.FileInfo, err error) error {
+		if err != nil || f.IsDir() {
+			return nil
+		}
+		if filepath.Ext(p) == ".csv" {
+			reportFiles = append(reportFiles, f.Name())
+		}
+		return nil
+	})
+	return reportFiles
 }
 
-func buildRowsFromCsv(csvPath string) []*sheets.RowData {
-	fmt.Println("reading " + csvPath)
-	f, _ := os.Open(csvPath)
+func buildRowsFromCsv(csvPath string) ([]*sheets.RowData, error) {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
 	r := csv.NewReader(bufio.NewReader(f))
-	result := make([]*sheets.RowData, 0)
+	records := make([][]string, 0)
 	for {
 		record, err := r.Read()
 		// Stop at EOF.
 		if err == io.EOF {
 			break
 		}
-		row := make([]*sheets.CellData, 0)
-		for value := range record {
-			row = append(row, &sheets.CellData{
-				UserEnteredValue: &sheets.ExtendedValue{
-					StringValue: record[value],
-				},
-			})
+		if err != nil {
+			return nil, err
 		}
-		result = append(result, &sheets.RowData{
-			Values: row,
-		})
-
+		records = append(records, record)
 	}
-	return result
+	return gsheets.ValuesToCellData(records), nil
 }
 
 // [END sheets_quickstart]
@@ -0,0 +1,195 @@
+package export
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteExporter loads the per-repo CSV reports into a SQLite database -
+// one table per report file (stargazers, repos, contributions,
+// attributes, ...) - so the data can be queried with ad-hoc SQL instead of
+// opened in a spreadsheet. Each report's own columns already single out
+// one kind of entity (a stargazer, a repo, a contribution, ...), so a
+// table per report is the natural normalization here; this exporter
+// doesn't go further and infer relationships between them.
+type SQLiteExporter struct {
+	// DBPath is the sqlite database file the reports are loaded into.
+	DBPath string
+}
+
+// columnType is the SQLite column affinity inferred for a CSV column.
+type columnType int
+
+const (
+	columnText columnType = iota
+	columnInteger
+	columnReal
+)
+
+func (t columnType) String() string {
+	switch t {
+	case columnInteger:
+		return "INTEGER"
+	case columnReal:
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
+
+// Export implements Exporter.
+func (e *SQLiteExporter) Export(ctx context.Context, rootPath, repo string) error {
+	db, err := sql.Open("sqlite3", e.DBPath)
+	if err != nil {
+		return fmt.Errorf("sqlite: opening %s: %w", e.DBPath, err)
+	}
+	defer db.Close()
+
+	for _, f := range findCSVFiles(rootPath, repo) {
+		table := strings.TrimSuffix(f, filepath.Ext(f))
+		if err := loadCSVIntoTable(ctx, db, path.Join(rootPath, repo, f), table); err != nil {
+			return fmt.Errorf("sqlite: loading %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+// loadCSVIntoTable drops table if it exists, recreates it - with column
+// affinities inferred from the CSV's own values - and inserts every record
+// into it.
+func loadCSVIntoTable(ctx context.Context, db *sql.DB, csvPath, table string) error {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(f))
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	columnTypes := inferColumnTypes(header, records)
+
+	// Drop and recreate the table on every run instead of just INSERTing
+	// into whatever's already there, so re-running the exporter against
+	// the same repo (a cron job, a retry after a partial failure) doesn't
+	// duplicate every row. This also picks up any column/type changes in
+	// the CSV since the last run.
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table)); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, createTableSQL(table, header, columnTypes)); err != nil {
+		return err
+	}
+
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(header, ", "), placeholders(len(header)))
+	stmt, err := db.PrepareContext(ctx, insertSQL)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		args := make([]interface{}, len(record))
+		for i, v := range record {
+			args[i] = columnValue(v, columnTypes[i])
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inferColumnTypes looks at every value in each column and picks the
+// narrowest affinity (INTEGER, then REAL, then TEXT) that fits all of
+// them. Empty cells are ignored - they become NULL regardless of the
+// column's affinity - so a column that's otherwise all-numeric with a few
+// blanks still gets a numeric column.
+func inferColumnTypes(header []string, records [][]string) []columnType {
+	types := make([]columnType, len(header))
+	for col := range header {
+		t := columnInteger
+		for _, record := range records {
+			if col >= len(record) || record[col] == "" {
+				continue
+			}
+			v := record[col]
+			if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+				continue
+			}
+			// ParseFloat itself accepts "NaN" and "Inf"/"+Inf"/"-Inf",
+			// which would otherwise pull an all-numeric column down to
+			// REAL just because one row spelled out "NaN".
+			if n, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+				if t == columnInteger {
+					t = columnReal
+				}
+				continue
+			}
+			t = columnText
+			break
+		}
+		types[col] = t
+	}
+	return types
+}
+
+func createTableSQL(table string, columns []string, columnTypes []columnType) string {
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = fmt.Sprintf("%s %s", c, columnTypes[i])
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(defs, ", "))
+}
+
+func placeholders(n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = "?"
+	}
+	return strings.Join(ph, ", ")
+}
+
+// columnValue converts a raw CSV cell to the Go type matching t, so the
+// value round-trips through SQLite with the column's real affinity
+// instead of being coerced back to text.
+func columnValue(v string, t columnType) interface{} {
+	if v == "" {
+		return nil
+	}
+	switch t {
+	case columnInteger:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	case columnReal:
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	default:
+		return v
+	}
+}
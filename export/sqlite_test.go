@@ -0,0 +1,86 @@
+package export
+
+import "testing"
+
+func TestInferColumnTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		records [][]string
+		want    []columnType
+	}{
+		{
+			name:    "all integers",
+			header:  []string{"stars"},
+			records: [][]string{{"1"}, {"0"}, {"42"}},
+			want:    []columnType{columnInteger},
+		},
+		{
+			name:    "mixed int and float becomes real",
+			header:  []string{"score"},
+			records: [][]string{{"1"}, {"2.5"}},
+			want:    []columnType{columnReal},
+		},
+		{
+			name:    "non-numeric value makes the whole column text",
+			header:  []string{"login"},
+			records: [][]string{{"1"}, {"octocat"}},
+			want:    []columnType{columnText},
+		},
+		{
+			name:    "blank cells are ignored",
+			header:  []string{"stars"},
+			records: [][]string{{"1"}, {""}, {"3"}},
+			want:    []columnType{columnInteger},
+		},
+		{
+			name:    "NaN stays text, not real",
+			header:  []string{"stars"},
+			records: [][]string{{"1"}, {"NaN"}},
+			want:    []columnType{columnText},
+		},
+		{
+			name:    "multiple columns inferred independently",
+			header:  []string{"stars", "login"},
+			records: [][]string{{"1", "octocat"}, {"2", "torvalds"}},
+			want:    []columnType{columnInteger, columnText},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inferColumnTypes(tt.header, tt.records)
+			if len(got) != len(tt.want) {
+				t.Fatalf("inferColumnTypes() returned %d types, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("column %d: got %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestColumnValue(t *testing.T) {
+	tests := []struct {
+		name string
+		v    string
+		t    columnType
+		want interface{}
+	}{
+		{"empty is nil", "", columnInteger, nil},
+		{"integer", "42", columnInteger, int64(42)},
+		{"real", "3.14", columnReal, 3.14},
+		{"text", "octocat", columnText, "octocat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := columnValue(tt.v, tt.t)
+			if got != tt.want {
+				t.Errorf("columnValue(%q, %v) = %v, want %v", tt.v, tt.t, got, tt.want)
+			}
+		})
+	}
+}
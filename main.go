@@ -0,0 +1,52 @@
+// Command stargazers exports the per-repo stargazer CSV reports under a
+// root directory to one or more sinks: a Google Sheet, a Drive folder, a
+// SQLite database, or JSON Lines files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/DrMegavolt/stargazers/export"
+)
+
+func main() {
+	rootPath := flag.String("root", ".", "root path containing the per-repo CSV report directories")
+	repo := flag.String("repo", "", "repo (owner/name) whose reports to export")
+	exportTo := flag.String("export", "sheets", `comma-separated list of exporters to run: "sheets", "drive", "sqlite", "jsonlines"`)
+	folderID := flag.String("folder-id", "", "Drive folder ID the sheets/drive exporters file under (defaults to the stargazers folder)")
+	dbPath := flag.String("db", "stargazers.db", "sqlite database path for the sqlite exporter")
+	outDir := flag.String("out-dir", ".", "output directory for the jsonlines exporter")
+	flag.Parse()
+
+	if *repo == "" {
+		fmt.Fprintln(os.Stderr, "missing required -repo flag")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	opts := export.Options{
+		FolderID: *folderID,
+		DBPath:   *dbPath,
+		OutDir:   *outDir,
+	}
+
+	for _, kind := range strings.Split(*exportTo, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		exporter, err := export.New(kind, opts)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		if err := exporter.Export(ctx, *rootPath, *repo); err != nil {
+			log.Fatalf("export %s: %v", kind, err)
+		}
+	}
+}